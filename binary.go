@@ -0,0 +1,300 @@
+package radix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var binaryMagic = [4]byte{'R', 'D', 'X', '1'}
+
+const binaryVersion = 1
+
+// minNodeBytes is the fewest bytes a single encoded node can occupy
+// (empty prefix, no children, no leaf), used to reject an implausible
+// nodeCount before allocating anything for it.
+const minNodeBytes = 3
+
+// MarshalBinary encodes the tree into a compact pre-order representation,
+// so a large IP database (millions of entries) can be built once and
+// mmap-loaded on process start instead of being rebuilt via Insert in a
+// loop. Values are always serialized with encoding/gob, and MarshalBinary
+// returns an error for a value gob cannot encode; UnmarshalBinary accepts
+// a custom decodeValue for the decoding side only.
+//
+// Format: magic(4) || version(1) || varint(nodeCount) || flags(1),
+// followed by nodeCount nodes in pre-order, each encoded as
+// varint(prefixLen) || prefix || varint(numChildren) ||
+// {childLabel(1), varint(childIndex)}* || hasLeaf(1) ||
+// if hasLeaf: varint(keyLen) || key || varint(valLen) || val.
+// childIndex refers to the child's position in the pre-order sequence,
+// which by construction is always greater than the referencing node's
+// own position.
+func (t *Tree) MarshalBinary() ([]byte, error) {
+	nodes := preOrderNodes(t.root, make([]*node, 0, t.size*2+1))
+	index := make(map[*node]int, len(nodes))
+	for i, n := range nodes {
+		index[n] = i
+	}
+
+	var buf bytes.Buffer
+	buf.Write(binaryMagic[:])
+	buf.WriteByte(binaryVersion)
+	writeUvarint(&buf, uint64(len(nodes)))
+	buf.WriteByte(0) // flags: bit 0 is reserved for a future bit-level format
+
+	for _, n := range nodes {
+		writeUvarint(&buf, uint64(len(n.prefix)))
+		buf.Write(n.prefix)
+		writeUvarint(&buf, uint64(len(n.edges)))
+		for _, e := range n.edges {
+			buf.WriteByte(e.label)
+			writeUvarint(&buf, uint64(index[e.node]))
+		}
+		if n.leaf == nil {
+			buf.WriteByte(0)
+			continue
+		}
+		valBytes, err := gobEncode(n.leaf.val)
+		if err != nil {
+			return nil, fmt.Errorf("radix: encoding value for key %q: %w", n.leaf.key, err)
+		}
+		buf.WriteByte(1)
+		writeUvarint(&buf, uint64(len(n.leaf.key)))
+		buf.Write(n.leaf.key)
+		writeUvarint(&buf, uint64(len(valBytes)))
+		buf.Write(valBytes)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo writes the same representation as MarshalBinary to w.
+func (t *Tree) WriteTo(w io.Writer) (int64, error) {
+	data, err := t.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// preOrderNodes appends n and every node reachable from it to out, in
+// pre-order, and returns the extended slice.
+func preOrderNodes(n *node, out []*node) []*node {
+	out = append(out, n)
+	for _, e := range n.edges {
+		out = preOrderNodes(e.node, out)
+	}
+	return out
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	buf.Write(tmp[:n])
+}
+
+// UnmarshalBinary decodes a tree previously produced by MarshalBinary. It
+// bounds-checks every length and index against the remaining input and
+// rejects child indices that don't point strictly forward or that are
+// claimed by more than one parent, so it is safe to call on untrusted or
+// corrupt data. A forward-only check alone would still accept a node
+// referenced by two different parents, turning the decoded pre-order list
+// into a DAG that silently re-expands into an exponentially larger tree
+// on the first full walk. If decodeValue is nil, values are decoded with
+// encoding/gob.
+func UnmarshalBinary(data []byte, decodeValue func([]byte) (interface{}, error)) (*Tree, error) {
+	if decodeValue == nil {
+		decodeValue = gobDecode
+	}
+
+	r := &byteReader{data: data}
+	var magic [4]byte
+	if err := r.readFull(magic[:]); err != nil {
+		return nil, fmt.Errorf("radix: reading magic: %w", err)
+	}
+	if magic != binaryMagic {
+		return nil, errors.New("radix: bad magic")
+	}
+	version, err := r.readByte()
+	if err != nil {
+		return nil, fmt.Errorf("radix: reading version: %w", err)
+	}
+	if version != binaryVersion {
+		return nil, fmt.Errorf("radix: unsupported version %d", version)
+	}
+	nodeCount64, err := r.readUvarint()
+	if err != nil {
+		return nil, fmt.Errorf("radix: reading node count: %w", err)
+	}
+	if nodeCount64 > uint64(r.remaining()/minNodeBytes) {
+		return nil, errors.New("radix: implausible node count")
+	}
+	if _, err := r.readByte(); err != nil { // flags, currently unused
+		return nil, fmt.Errorf("radix: reading flags: %w", err)
+	}
+
+	nodeCount := int(nodeCount64)
+	nodes := make([]*node, nodeCount)
+	for i := range nodes {
+		nodes[i] = &node{}
+	}
+	claimed := make([]bool, nodeCount)
+
+	size := 0
+	for i := 0; i < nodeCount; i++ {
+		n := nodes[i]
+
+		prefixLen, err := r.readUvarint()
+		if err != nil {
+			return nil, fmt.Errorf("radix: node %d: reading prefix length: %w", i, err)
+		}
+		prefix, err := r.readBytes(int(prefixLen))
+		if err != nil {
+			return nil, fmt.Errorf("radix: node %d: reading prefix: %w", i, err)
+		}
+		n.prefix = prefix
+
+		numChildren, err := r.readUvarint()
+		if err != nil {
+			return nil, fmt.Errorf("radix: node %d: reading child count: %w", i, err)
+		}
+		if numChildren > uint64(r.remaining()/2) {
+			return nil, fmt.Errorf("radix: node %d: implausible child count", i)
+		}
+		edgeList := make(edges, 0, numChildren)
+		for c := uint64(0); c < numChildren; c++ {
+			label, err := r.readByte()
+			if err != nil {
+				return nil, fmt.Errorf("radix: node %d: reading child label: %w", i, err)
+			}
+			childIdx, err := r.readUvarint()
+			if err != nil {
+				return nil, fmt.Errorf("radix: node %d: reading child index: %w", i, err)
+			}
+			if childIdx <= uint64(i) || childIdx >= uint64(nodeCount) {
+				return nil, fmt.Errorf("radix: node %d: child index %d does not point forward", i, childIdx)
+			}
+			if claimed[childIdx] {
+				return nil, fmt.Errorf("radix: node %d: child index %d is already claimed by another parent", i, childIdx)
+			}
+			claimed[childIdx] = true
+			edgeList = append(edgeList, edge{label: label, node: nodes[childIdx]})
+		}
+		edgeList.Sort()
+		n.edges = edgeList
+
+		hasLeaf, err := r.readByte()
+		if err != nil {
+			return nil, fmt.Errorf("radix: node %d: reading leaf marker: %w", i, err)
+		}
+		switch hasLeaf {
+		case 0:
+		case 1:
+			keyLen, err := r.readUvarint()
+			if err != nil {
+				return nil, fmt.Errorf("radix: node %d: reading key length: %w", i, err)
+			}
+			key, err := r.readBytes(int(keyLen))
+			if err != nil {
+				return nil, fmt.Errorf("radix: node %d: reading key: %w", i, err)
+			}
+			valLen, err := r.readUvarint()
+			if err != nil {
+				return nil, fmt.Errorf("radix: node %d: reading value length: %w", i, err)
+			}
+			valBytes, err := r.readBytes(int(valLen))
+			if err != nil {
+				return nil, fmt.Errorf("radix: node %d: reading value: %w", i, err)
+			}
+			val, err := decodeValue(valBytes)
+			if err != nil {
+				return nil, fmt.Errorf("radix: node %d: decoding value: %w", i, err)
+			}
+			n.leaf = &leafNode{key: key, val: val}
+			size++
+		default:
+			return nil, fmt.Errorf("radix: node %d: bad leaf marker %d", i, hasLeaf)
+		}
+	}
+
+	if nodeCount == 0 {
+		return New(), nil
+	}
+	return &Tree{root: nodes[0], size: size}, nil
+}
+
+// ReadFrom reads a full UnmarshalBinary-encoded tree from r using the
+// default gob value decoder and replaces t's contents with it.
+func (t *Tree) ReadFrom(r io.Reader) (int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return int64(len(data)), err
+	}
+	decoded, err := UnmarshalBinary(data, nil)
+	if err != nil {
+		return int64(len(data)), err
+	}
+	*t = *decoded
+	return int64(len(data)), nil
+}
+
+func gobDecode(b []byte) (interface{}, error) {
+	var v interface{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// byteReader is a bounds-checked cursor over a []byte, used so
+// UnmarshalBinary can reject truncated or oversized fields with an error
+// instead of panicking on malformed input.
+type byteReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *byteReader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *byteReader) readFull(dst []byte) error {
+	if r.remaining() < len(dst) {
+		return io.ErrUnexpectedEOF
+	}
+	copy(dst, r.data[r.pos:])
+	r.pos += len(dst)
+	return nil
+}
+
+func (r *byteReader) readByte() (byte, error) {
+	if r.remaining() < 1 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *byteReader) readBytes(n int) ([]byte, error) {
+	if n < 0 || n > r.remaining() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	out := make([]byte, n)
+	copy(out, r.data[r.pos:r.pos+n])
+	r.pos += n
+	return out, nil
+}
+
+func (r *byteReader) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.pos += n
+	return v, nil
+}
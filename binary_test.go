@@ -0,0 +1,184 @@
+package radix
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMarshalUnmarshalBinary(t *testing.T) {
+	r := New()
+	keys := [][]byte{{}, {2}, {2, 3}, {2, 3, 4}, {33}, {44}}
+	for i, k := range keys {
+		r.Insert(k, i)
+	}
+
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	decoded, err := UnmarshalBinary(data, nil)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded.Len() != r.Len() {
+		t.Fatalf("bad length: %v %v", decoded.Len(), r.Len())
+	}
+	for i, k := range keys {
+		v, ok := decoded.Get(k)
+		if !ok || v != i {
+			t.Fatalf("mis-match for %v: %v %v", k, v, ok)
+		}
+	}
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	r := New()
+	r.Insert([]byte("hello"), "world")
+	r.Insert([]byte("help"), "me")
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	out := New()
+	if _, err := out.ReadFrom(&buf); err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	if out.Len() != r.Len() {
+		t.Fatalf("bad length: %v %v", out.Len(), r.Len())
+	}
+	if v, ok := out.Get([]byte("hello")); !ok || v != "world" {
+		t.Fatalf("bad value: %v %v", v, ok)
+	}
+}
+
+func TestUnmarshalBinaryRejectsBadInput(t *testing.T) {
+	r := New()
+	r.Insert([]byte{1}, true)
+	r.Insert([]byte{2}, true)
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	if _, err := UnmarshalBinary(data[:len(data)-1], nil); err == nil {
+		t.Fatalf("expected an error for truncated input")
+	}
+	if _, err := UnmarshalBinary([]byte("not a radix tree"), nil); err == nil {
+		t.Fatalf("expected an error for bad magic")
+	}
+}
+
+func TestUnmarshalBinaryRejectsDuplicateChildIndex(t *testing.T) {
+	// Hand-crafts a 4-node payload where node 1 and node 2 both list node
+	// 3 as their child. Each index still points strictly forward, so the
+	// old check alone would accept it, but the result is a DAG - walking
+	// it would visit node 3's subtree twice, and a deeper chain of such
+	// shared references can make a full Walk/Get cost exponential in the
+	// encoded node count.
+	var buf bytes.Buffer
+	buf.Write(binaryMagic[:])
+	buf.WriteByte(binaryVersion)
+	writeUvarint(&buf, 4) // nodeCount
+	buf.WriteByte(0)      // flags
+
+	// node 0: root, children 'a' -> 1, 'b' -> 2
+	writeUvarint(&buf, 0)
+	writeUvarint(&buf, 2)
+	buf.WriteByte('a')
+	writeUvarint(&buf, 1)
+	buf.WriteByte('b')
+	writeUvarint(&buf, 2)
+	buf.WriteByte(0)
+
+	// node 1: child 'x' -> 3
+	writeUvarint(&buf, 0)
+	writeUvarint(&buf, 1)
+	buf.WriteByte('x')
+	writeUvarint(&buf, 3)
+	buf.WriteByte(0)
+
+	// node 2: child 'x' -> 3, same target as node 1's child
+	writeUvarint(&buf, 0)
+	writeUvarint(&buf, 1)
+	buf.WriteByte('x')
+	writeUvarint(&buf, 3)
+	buf.WriteByte(0)
+
+	// node 3: leaf
+	writeUvarint(&buf, 0)
+	writeUvarint(&buf, 0)
+	buf.WriteByte(1)
+	key := []byte("leaf")
+	writeUvarint(&buf, uint64(len(key)))
+	buf.Write(key)
+	valBytes, err := gobEncode(42)
+	if err != nil {
+		t.Fatalf("gobEncode: %v", err)
+	}
+	writeUvarint(&buf, uint64(len(valBytes)))
+	buf.Write(valBytes)
+
+	if _, err := UnmarshalBinary(buf.Bytes(), nil); err == nil {
+		t.Fatalf("expected an error for a child index claimed by two parents")
+	}
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	r := New()
+	r.Insert([]byte("a"), 1)
+	r.Insert([]byte("ab"), 2)
+	seed, err := r.MarshalBinary()
+	if err != nil {
+		f.Fatalf("marshal: %v", err)
+	}
+	f.Add(seed)
+	f.Add([]byte("short"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Must never panic on arbitrary input, whatever it decides to
+		// return.
+		_, _ = UnmarshalBinary(data, nil)
+	})
+}
+
+func FuzzTreeBinaryRoundTrip(f *testing.F) {
+	f.Add([]byte{3, 1, 'a', 2, 'a', 'b', 1, 'c'})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := New()
+		want := make(map[string]int)
+		for i := 0; i+1 < len(data); {
+			klen := int(data[i])%5 + 1
+			i++
+			if i+klen > len(data) {
+				break
+			}
+			key := append([]byte(nil), data[i:i+klen]...)
+			i += klen
+			r.Insert(key, len(want))
+			want[string(key)] = len(want)
+		}
+
+		encoded, err := r.MarshalBinary()
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		decoded, err := UnmarshalBinary(encoded, nil)
+		if err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if decoded.Len() != r.Len() {
+			t.Fatalf("length mismatch: %d %d", decoded.Len(), r.Len())
+		}
+		for k, v := range want {
+			got, ok := decoded.Get([]byte(k))
+			if !ok || got != v {
+				t.Fatalf("mis-match for %q: %v %v, want %v", k, got, ok, v)
+			}
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package radix
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"hash"
+	"reflect"
+)
+
+// ValueEncoder serializes a value into bytes for HashInto. encoding/gob
+// is used by default (and always by Hash); pass a custom ValueEncoder to
+// HashInto when values aren't gob-encodable or a cheaper encoding is
+// available.
+type ValueEncoder func(v interface{}) ([]byte, error)
+
+func gobEncode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Hash returns a deterministic SHA-256 fingerprint of the tree's
+// (key, value) pairs, letting callers reconciling a large set against an
+// upstream source cheaply detect "nothing changed" before diffing. The
+// result is cached and invalidated on the next Insert, Delete or
+// DeletePrefix.
+//
+// Hash panics if a stored value is not gob-encodable: a value an Insert
+// call accepted but Hash cannot serialize means the walk can only see a
+// truncated subset of the tree, and caching or returning a digest over
+// that subset as if it covered everything would be worse than failing
+// loudly. Callers with such values should use HashInto with their own
+// ValueEncoder instead.
+func (t *Tree) Hash() [32]byte {
+	if t.hash != nil {
+		return *t.hash
+	}
+	h := sha256.New()
+	if err := t.HashInto(h, gobEncode); err != nil {
+		panic("radix: Hash: " + err.Error())
+	}
+	var out [32]byte
+	copy(out[:], h.Sum(nil))
+	t.hash = &out
+	return out
+}
+
+// HashInto writes the same deterministic byte stream used by Hash into h,
+// letting callers combine it with other hashes or use a hash.Hash other
+// than SHA-256. If encode is nil, values are serialized with
+// encoding/gob.
+func (t *Tree) HashInto(h hash.Hash, encode ValueEncoder) error {
+	if encode == nil {
+		encode = gobEncode
+	}
+	var lenBuf [binary.MaxVarintLen64]byte
+	var walkErr error
+	t.Walk(func(k []byte, v interface{}) bool {
+		vb, err := encode(v)
+		if err != nil {
+			walkErr = err
+			return true
+		}
+		n := binary.PutUvarint(lenBuf[:], uint64(len(k)))
+		h.Write(lenBuf[:n])
+		h.Write(k)
+		n = binary.PutUvarint(lenBuf[:], uint64(len(vb)))
+		h.Write(lenBuf[:n])
+		h.Write(vb)
+		return false
+	})
+	return walkErr
+}
+
+// Diff compares t against other, walking both in Walk's pre-order, which
+// is sorted key order, and returns the keys that exist only in other
+// (added), only in t (removed), or in both with unequal values (changed).
+// This lets a caller who already confirmed Hash() differs compute a
+// minimal patch without diffing the full sets by hand.
+func (t *Tree) Diff(other *Tree) (added, removed, changed [][]byte) {
+	a := t.entries()
+	b := other.entries()
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch bytes.Compare(a[i].key, b[j].key) {
+		case 0:
+			if !reflect.DeepEqual(a[i].val, b[j].val) {
+				changed = append(changed, a[i].key)
+			}
+			i++
+			j++
+		case -1:
+			removed = append(removed, a[i].key)
+			i++
+		default:
+			added = append(added, b[j].key)
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		removed = append(removed, a[i].key)
+	}
+	for ; j < len(b); j++ {
+		added = append(added, b[j].key)
+	}
+	return added, removed, changed
+}
+
+type treeEntry struct {
+	key []byte
+	val interface{}
+}
+
+// entries returns the tree's (key, value) pairs in Walk order, which is
+// the full sorted order since leaves sort before the keys that extend
+// them and edges are stored sorted by label.
+func (t *Tree) entries() []treeEntry {
+	out := make([]treeEntry, 0, t.size)
+	t.Walk(func(k []byte, v interface{}) bool {
+		out = append(out, treeEntry{key: k, val: v})
+		return false
+	})
+	return out
+}
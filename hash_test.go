@@ -0,0 +1,120 @@
+package radix
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+)
+
+func TestHashStableAndInvalidated(t *testing.T) {
+	r := New()
+	r.Insert([]byte("a"), 1)
+	r.Insert([]byte("b"), 2)
+
+	h1 := r.Hash()
+	h2 := r.Hash()
+	if h1 != h2 {
+		t.Fatalf("hash is not stable across calls with no mutation")
+	}
+
+	other := New()
+	other.Insert([]byte("b"), 2)
+	other.Insert([]byte("a"), 1)
+	if r.Hash() != other.Hash() {
+		t.Fatalf("hash depends on insertion order, should only depend on contents")
+	}
+
+	r.Insert([]byte("c"), 3)
+	if r.Hash() == h1 {
+		t.Fatalf("hash was not invalidated after Insert")
+	}
+}
+
+func TestHashPanicsOnUnencodableValue(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected Hash to panic on a value gob cannot encode")
+		}
+	}()
+
+	r := New()
+	r.Insert([]byte("a"), func() {})
+	r.Hash()
+}
+
+func TestHashIntoReturnsErrorForUnencodableValue(t *testing.T) {
+	r := New()
+	r.Insert([]byte("a"), func() {})
+
+	if err := r.HashInto(sha256.New(), nil); err == nil {
+		t.Fatalf("expected an error, not a silently truncated hash")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := New()
+	a.Insert([]byte("keep"), 1)
+	a.Insert([]byte("remove-me"), 2)
+	a.Insert([]byte("change-me"), 3)
+
+	b := New()
+	b.Insert([]byte("keep"), 1)
+	b.Insert([]byte("change-me"), 30)
+	b.Insert([]byte("add-me"), 4)
+
+	added, removed, changed := a.Diff(b)
+
+	assertKeys(t, "added", added, "add-me")
+	assertKeys(t, "removed", removed, "remove-me")
+	assertKeys(t, "changed", changed, "change-me")
+}
+
+func assertKeys(t *testing.T, label string, got [][]byte, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: bad count: %v want %v", label, got, want)
+	}
+	for i, w := range want {
+		if string(got[i]) != w {
+			t.Fatalf("%s: mis-match at %d: %v want %v", label, i, string(got[i]), w)
+		}
+	}
+}
+
+func BenchmarkHashTree(b *testing.B) {
+	r := New()
+	for i := 0; i < 10000; i++ {
+		gen := generateIPv6()
+		r.Insert(gen.To16(), i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.hash = nil
+		r.Hash()
+	}
+}
+
+func BenchmarkHashMap(b *testing.B) {
+	m := make(map[string]interface{})
+	for i := 0; i < 10000; i++ {
+		gen := generateIPv6()
+		m[string(gen.To16())] = i
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h := sha256.New()
+		var lenBuf [binary.MaxVarintLen64]byte
+		for k, v := range m {
+			vb, err := gobEncode(v)
+			if err != nil {
+				b.Fatal(err)
+			}
+			n := binary.PutUvarint(lenBuf[:], uint64(len(k)))
+			h.Write(lenBuf[:n])
+			h.Write([]byte(k))
+			n = binary.PutUvarint(lenBuf[:], uint64(len(vb)))
+			h.Write(lenBuf[:n])
+			h.Write(vb)
+		}
+	}
+}
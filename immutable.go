@@ -0,0 +1,426 @@
+package radix
+
+import (
+	"bytes"
+	"sort"
+)
+
+// ileafNode is used to represent a value in an ImmutableTree
+type ileafNode struct {
+	key []byte
+	val interface{}
+}
+
+// iedge is used to represent an edge node in an ImmutableTree
+type iedge struct {
+	label byte
+	node  *inode
+}
+
+type iedges []iedge
+
+func (e iedges) Len() int           { return len(e) }
+func (e iedges) Less(i, j int) bool { return e[i].label < e[j].label }
+func (e iedges) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+
+// inode is a node in an ImmutableTree. Nodes are never mutated once they
+// are reachable from a published *ImmutableTree: a Txn copies a node the
+// first time it needs to change it and leaves the original, and everything
+// unreached from the copy, untouched.
+type inode struct {
+	leaf   *ileafNode
+	prefix []byte
+	edges  iedges
+}
+
+func (n *inode) isLeaf() bool {
+	return n.leaf != nil
+}
+
+func (n *inode) copy() *inode {
+	nc := &inode{leaf: n.leaf, prefix: n.prefix}
+	if len(n.edges) != 0 {
+		nc.edges = make(iedges, len(n.edges))
+		copy(nc.edges, n.edges)
+	}
+	return nc
+}
+
+func (n *inode) getEdge(label byte) (int, *inode) {
+	num := len(n.edges)
+	idx := sort.Search(num, func(i int) bool {
+		return n.edges[i].label >= label
+	})
+	if idx < num && n.edges[idx].label == label {
+		return idx, n.edges[idx].node
+	}
+	return -1, nil
+}
+
+func (n *inode) addEdge(e iedge) {
+	n.edges = append(n.edges, e)
+	sort.Sort(n.edges)
+}
+
+func (n *inode) delEdge(label byte) {
+	num := len(n.edges)
+	idx := sort.Search(num, func(i int) bool {
+		return n.edges[i].label >= label
+	})
+	if idx < num && n.edges[idx].label == label {
+		copy(n.edges[idx:], n.edges[idx+1:])
+		n.edges[len(n.edges)-1] = iedge{}
+		n.edges = n.edges[:len(n.edges)-1]
+	}
+}
+
+// mergeChild folds n's single remaining child into n itself. The caller
+// must already hold a private copy of n; the child is only read, never
+// mutated, so it does not need to be copied too. The child's edge slice is
+// copied rather than shared, so later edits to n's edges cannot write into
+// the child's backing array.
+func (n *inode) mergeChild() {
+	child := n.edges[0].node
+	prefix := make([]byte, 0, len(n.prefix)+len(child.prefix))
+	prefix = append(prefix, n.prefix...)
+	prefix = append(prefix, child.prefix...)
+	n.prefix = prefix
+	n.leaf = child.leaf
+	n.edges = nil
+	if len(child.edges) != 0 {
+		n.edges = make(iedges, len(child.edges))
+		copy(n.edges, child.edges)
+	}
+}
+
+// ImmutableTree is a persistent radix tree: Insert, Delete and
+// DeletePrefix return a new *ImmutableTree and share every subtree that
+// wasn't on the path to the modified node with the old one via
+// copy-on-write. A *ImmutableTree is never mutated after it is published,
+// so readers can Get/LongestPrefix/Walk it concurrently without a lock
+// while a writer builds the next snapshot - the natural fit for swapping
+// an atomic pointer on config reload while lookups are in flight.
+type ImmutableTree struct {
+	root *inode
+	size int
+}
+
+// NewImmutable returns an empty ImmutableTree
+func NewImmutable() *ImmutableTree {
+	return &ImmutableTree{root: &inode{}}
+}
+
+// Len is used to return the number of elements in the tree
+func (t *ImmutableTree) Len() int {
+	return t.size
+}
+
+// Txn starts a transaction that can batch multiple edits into a single
+// new root. Call Commit to publish the result as a new *ImmutableTree;
+// the receiver is left untouched either way.
+func (t *ImmutableTree) Txn() *Txn {
+	return &Txn{root: t.root, size: t.size}
+}
+
+// Insert is a convenience for a single-edit Txn. It returns the new tree
+// along with the previous value, if any was set.
+func (t *ImmutableTree) Insert(k []byte, v interface{}) (*ImmutableTree, interface{}, bool) {
+	txn := t.Txn()
+	old, updated := txn.Insert(k, v)
+	return txn.Commit(), old, updated
+}
+
+// Delete is a convenience for a single-edit Txn. It returns the new tree
+// along with the previous value, if any was deleted.
+func (t *ImmutableTree) Delete(k []byte) (*ImmutableTree, interface{}, bool) {
+	txn := t.Txn()
+	old, deleted := txn.Delete(k)
+	return txn.Commit(), old, deleted
+}
+
+// DeletePrefix is a convenience for a single-edit Txn. It returns the new
+// tree along with the number of entries removed.
+func (t *ImmutableTree) DeletePrefix(prefix []byte) (*ImmutableTree, int) {
+	txn := t.Txn()
+	numDel := txn.DeletePrefix(prefix)
+	return txn.Commit(), numDel
+}
+
+// Get is used to lookup a specific key, returning the value and if it was
+// found. Safe to call concurrently with readers and writers of other
+// *ImmutableTree snapshots.
+func (t *ImmutableTree) Get(k []byte) (interface{}, bool) {
+	n := t.root
+	search := k
+	for {
+		if len(search) == 0 {
+			if n.isLeaf() {
+				return n.leaf.val, true
+			}
+			break
+		}
+		_, child := n.getEdge(search[0])
+		if child == nil {
+			break
+		}
+		if bytes.HasPrefix(search, child.prefix) {
+			search = search[len(child.prefix):]
+			n = child
+		} else {
+			break
+		}
+	}
+	return nil, false
+}
+
+// LongestPrefix is like Get, but instead of an exact match, it will
+// return the longest prefix match.
+func (t *ImmutableTree) LongestPrefix(k []byte) ([]byte, interface{}, bool) {
+	var last *ileafNode
+	n := t.root
+	search := k
+	for {
+		if n.isLeaf() {
+			last = n.leaf
+		}
+		if len(search) == 0 {
+			break
+		}
+		_, child := n.getEdge(search[0])
+		if child == nil {
+			break
+		}
+		if bytes.HasPrefix(search, child.prefix) {
+			search = search[len(child.prefix):]
+			n = child
+		} else {
+			break
+		}
+	}
+	if last != nil {
+		return last.key, last.val, true
+	}
+	return nil, nil, false
+}
+
+// Walk is used to walk the tree
+func (t *ImmutableTree) Walk(fn WalkFn) {
+	recursiveIWalk(t.root, fn)
+}
+
+func recursiveIWalk(n *inode, fn WalkFn) bool {
+	if n.leaf != nil && fn(n.leaf.key, n.leaf.val) {
+		return true
+	}
+	for _, e := range n.edges {
+		if recursiveIWalk(e.node, fn) {
+			return true
+		}
+	}
+	return false
+}
+
+// Txn is a transaction against an ImmutableTree. It batches one or more
+// edits into a single new root, copying each node on the path to a
+// modified node at most once no matter how many times that node is
+// touched within the same Txn.
+type Txn struct {
+	root    *inode
+	size    int
+	mutated map[*inode]*inode
+}
+
+// writeNode returns a private, mutable copy of n for this transaction,
+// reusing the copy already made earlier in the same Txn if there is one.
+// A node already known to be a private copy (because writeNode produced
+// it earlier in this same Txn) is registered as its own cache entry, so
+// touching it again - which happens on every edit along a shared path,
+// e.g. the root on every Insert/Delete in a batch - returns it directly
+// instead of copying it again.
+func (t *Txn) writeNode(n *inode) *inode {
+	if t.mutated == nil {
+		t.mutated = make(map[*inode]*inode)
+	}
+	if nc, ok := t.mutated[n]; ok {
+		return nc
+	}
+	nc := n.copy()
+	t.mutated[n] = nc
+	t.mutated[nc] = nc
+	return nc
+}
+
+// Insert is used to add a new entry or update an existing entry. Returns
+// the old value and a bool indicating if any was set.
+func (t *Txn) Insert(k []byte, v interface{}) (interface{}, bool) {
+	newRoot, old, didUpdate := t.insert(t.root, k, k, v)
+	t.root = newRoot
+	if !didUpdate {
+		t.size++
+	}
+	return old, didUpdate
+}
+
+func (t *Txn) insert(n *inode, fullKey, search []byte, v interface{}) (*inode, interface{}, bool) {
+	if len(search) == 0 {
+		nc := t.writeNode(n)
+		if n.isLeaf() {
+			old := n.leaf.val
+			nc.leaf = &ileafNode{key: fullKey, val: v}
+			return nc, old, true
+		}
+		nc.leaf = &ileafNode{key: fullKey, val: v}
+		return nc, nil, false
+	}
+
+	idx, child := n.getEdge(search[0])
+	if child == nil {
+		nc := t.writeNode(n)
+		nc.addEdge(iedge{
+			label: search[0],
+			node: &inode{
+				leaf:   &ileafNode{key: fullKey, val: v},
+				prefix: search,
+			},
+		})
+		return nc, nil, false
+	}
+
+	commonPrefix := longestPrefix(search, child.prefix)
+	if commonPrefix == len(child.prefix) {
+		newChild, old, didUpdate := t.insert(child, fullKey, search[commonPrefix:], v)
+		nc := t.writeNode(n)
+		nc.edges[idx].node = newChild
+		return nc, old, didUpdate
+	}
+
+	// Split the edge
+	nc := t.writeNode(n)
+	splitNode := &inode{prefix: search[:commonPrefix]}
+	nc.edges[idx].node = splitNode
+
+	modChild := t.writeNode(child)
+	splitNode.addEdge(iedge{label: modChild.prefix[commonPrefix], node: modChild})
+	modChild.prefix = modChild.prefix[commonPrefix:]
+
+	leaf := &ileafNode{key: fullKey, val: v}
+	search = search[commonPrefix:]
+	if len(search) == 0 {
+		splitNode.leaf = leaf
+		return nc, nil, false
+	}
+	splitNode.addEdge(iedge{label: search[0], node: &inode{leaf: leaf, prefix: search}})
+	return nc, nil, false
+}
+
+// Delete is used to delete a key, returning the previous value and if it
+// was deleted.
+func (t *Txn) Delete(k []byte) (interface{}, bool) {
+	newRoot, leaf := t.delete(t.root, k, true)
+	if leaf == nil {
+		return nil, false
+	}
+	if newRoot != nil {
+		t.root = newRoot
+	}
+	t.size--
+	return leaf.val, true
+}
+
+func (t *Txn) delete(n *inode, search []byte, isRoot bool) (*inode, *ileafNode) {
+	if len(search) == 0 {
+		if !n.isLeaf() {
+			return nil, nil
+		}
+		nc := t.writeNode(n)
+		leaf := nc.leaf
+		nc.leaf = nil
+		if !isRoot && len(nc.edges) == 1 {
+			nc.mergeChild()
+		}
+		return nc, leaf
+	}
+
+	label := search[0]
+	idx, child := n.getEdge(label)
+	if child == nil || !bytes.HasPrefix(search, child.prefix) {
+		return nil, nil
+	}
+
+	newChild, leaf := t.delete(child, search[len(child.prefix):], false)
+	if leaf == nil {
+		return nil, nil
+	}
+
+	nc := t.writeNode(n)
+	if newChild.leaf == nil && len(newChild.edges) == 0 {
+		nc.delEdge(label)
+	} else {
+		nc.edges[idx].node = newChild
+	}
+	if !isRoot && !nc.isLeaf() && len(nc.edges) == 1 {
+		nc.mergeChild()
+	}
+	return nc, leaf
+}
+
+// DeletePrefix is used to delete the subtree under a prefix. Returns how
+// many entries were deleted.
+func (t *Txn) DeletePrefix(prefix []byte) int {
+	newRoot, numDel := t.deletePrefix(t.root, prefix, true)
+	if newRoot != nil {
+		t.root = newRoot
+	}
+	t.size -= numDel
+	return numDel
+}
+
+func (t *Txn) deletePrefix(n *inode, prefix []byte, isRoot bool) (*inode, int) {
+	if len(prefix) == 0 {
+		numDel := 0
+		recursiveIWalk(n, func(k []byte, v interface{}) bool {
+			numDel++
+			return false
+		})
+		nc := t.writeNode(n)
+		nc.leaf = nil
+		nc.edges = nil
+		return nc, numDel
+	}
+
+	label := prefix[0]
+	idx, child := n.getEdge(label)
+	if child == nil || (!bytes.HasPrefix(child.prefix, prefix) && !bytes.HasPrefix(prefix, child.prefix)) {
+		return nil, 0
+	}
+
+	var rem []byte
+	if len(child.prefix) > len(prefix) {
+		rem = prefix[len(prefix):]
+	} else {
+		rem = prefix[len(child.prefix):]
+	}
+	newChild, numDel := t.deletePrefix(child, rem, false)
+	if newChild == nil {
+		return nil, 0
+	}
+
+	nc := t.writeNode(n)
+	if newChild.leaf == nil && len(newChild.edges) == 0 {
+		nc.delEdge(label)
+	} else {
+		nc.edges[idx].node = newChild
+	}
+	if !isRoot && !nc.isLeaf() && len(nc.edges) == 1 {
+		nc.mergeChild()
+	}
+	return nc, numDel
+}
+
+// Commit finalizes the transaction, returning a new ImmutableTree that
+// reflects every edit made since Txn was called.
+func (t *Txn) Commit() *ImmutableTree {
+	nt := &ImmutableTree{root: t.root, size: t.size}
+	t.mutated = nil
+	return nt
+}
@@ -0,0 +1,168 @@
+package radix
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestImmutableInsertGetDelete(t *testing.T) {
+	var min, max []byte
+	inp := make(map[string]interface{})
+	for i := 0; i < 1000; i++ {
+		gen := generateIPv6()
+		inp[string(gen)] = i
+		if bytes.Compare(gen, min) < 0 || i == 0 {
+			min = gen
+		}
+		if bytes.Compare(gen, max) > 0 || i == 0 {
+			max = gen
+		}
+	}
+
+	r := NewImmutable()
+	for k, v := range inp {
+		r, _, _ = r.Insert([]byte(k), v)
+	}
+	if r.Len() != len(inp) {
+		t.Fatalf("bad length: %v %v", r.Len(), len(inp))
+	}
+
+	for k, v := range inp {
+		out, ok := r.Get([]byte(k))
+		if !ok {
+			t.Fatalf("missing key: %v", k)
+		}
+		if out != v {
+			t.Fatalf("value mis-match: %v %v", out, v)
+		}
+	}
+
+	for k, v := range inp {
+		var deleted bool
+		var out interface{}
+		r, out, deleted = r.Delete([]byte(k))
+		if !deleted {
+			t.Fatalf("missing key: %v", k)
+		}
+		if out != v {
+			t.Fatalf("value mis-match: %v %v", out, v)
+		}
+	}
+	if r.Len() != 0 {
+		t.Fatalf("bad length: %v", r.Len())
+	}
+}
+
+func TestImmutableDeletePrefix(t *testing.T) {
+	type exp struct {
+		inp        [][]byte
+		prefix     []byte
+		out        [][]byte
+		numDeleted int
+	}
+
+	cases := []exp{
+		{[][]byte{{}, {2}, {2, 3}, {2, 3, 4}, {33}, {44}}, []byte{2}, [][]byte{{}, {33}, {44}}, 3},
+		{[][]byte{{}, {2}, {2, 3}, {2, 3, 4}, {33}, {44}}, []byte{2, 3, 4}, [][]byte{{}, {2}, {2, 3}, {33}, {44}}, 1},
+		{[][]byte{{}, {2}, {2, 3}, {2, 3, 4}, {33}, {44}}, []byte{}, [][]byte{}, 6},
+	}
+
+	for _, test := range cases {
+		r := NewImmutable()
+		for _, ss := range test.inp {
+			r, _, _ = r.Insert(ss, true)
+		}
+
+		var numDeleted int
+		r, numDeleted = r.DeletePrefix(test.prefix)
+		if numDeleted != test.numDeleted {
+			t.Fatalf("bad delete, expected %v to be deleted but got %v", test.numDeleted, numDeleted)
+		}
+
+		out := [][]byte{}
+		r.Walk(func(s []byte, v interface{}) bool {
+			out = append(out, s)
+			return false
+		})
+		if !reflect.DeepEqual(out, test.out) {
+			t.Fatalf("mis-match: %v %v", out, test.out)
+		}
+	}
+}
+
+func TestImmutableSnapshotIsolation(t *testing.T) {
+	r0 := NewImmutable()
+	r0, _, _ = r0.Insert([]byte("a"), 1)
+	r0, _, _ = r0.Insert([]byte("ab"), 2)
+
+	r1, _, _ := r0.Insert([]byte("abc"), 3)
+	r1, _, _ = r1.Delete([]byte("a"))
+
+	// r0 must be untouched by edits made against the r1 lineage
+	if v, ok := r0.Get([]byte("a")); !ok || v != 1 {
+		t.Fatalf("r0 mutated: %v %v", v, ok)
+	}
+	if _, ok := r0.Get([]byte("abc")); ok {
+		t.Fatalf("r0 saw an insert made on r1")
+	}
+	if v, ok := r1.Get([]byte("ab")); !ok || v != 2 {
+		t.Fatalf("r1 missing unrelated key: %v %v", v, ok)
+	}
+	if _, ok := r1.Get([]byte("a")); ok {
+		t.Fatalf("r1 still has a key deleted from it")
+	}
+}
+
+func TestImmutableTxnBatch(t *testing.T) {
+	r := NewImmutable()
+	txn := r.Txn()
+	for i := 0; i < 100; i++ {
+		txn.Insert([]byte{byte(i)}, i)
+	}
+	for i := 0; i < 50; i++ {
+		txn.Delete([]byte{byte(i)})
+	}
+	r = txn.Commit()
+
+	if r.Len() != 50 {
+		t.Fatalf("bad length: %v", r.Len())
+	}
+	for i := 0; i < 50; i++ {
+		if _, ok := r.Get([]byte{byte(i)}); ok {
+			t.Fatalf("unexpected key %d", i)
+		}
+	}
+	for i := 50; i < 100; i++ {
+		v, ok := r.Get([]byte{byte(i)})
+		if !ok || v != i {
+			t.Fatalf("missing key %d: %v %v", i, v, ok)
+		}
+	}
+}
+
+func TestTxnWriteNodeCachesWithinTxn(t *testing.T) {
+	r := NewImmutable()
+	txn := r.Txn()
+
+	nc := txn.writeNode(txn.root)
+	if nc != txn.writeNode(nc) {
+		t.Fatalf("a node already privatized by this Txn must not be copied again")
+	}
+}
+
+func TestTxnBatchReusesPathCopies(t *testing.T) {
+	r := NewImmutable()
+	txn := r.Txn()
+
+	txn.Insert([]byte("aaa"), 0)
+	rootAfterFirst := txn.root
+	txn.Insert([]byte("aab"), 1)
+	rootAfterSecond := txn.root
+
+	// Both edits share the root as part of their path; the root copy made
+	// for the first edit must be reused, not replaced, by the second.
+	if rootAfterFirst != rootAfterSecond {
+		t.Fatalf("expected the same root node object to be reused across edits in one Txn")
+	}
+}
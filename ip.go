@@ -0,0 +1,106 @@
+package radix
+
+import "net/netip"
+
+// IPTree is a thin wrapper around PatriciaTree that speaks netip.Addr and
+// netip.Prefix directly, so IP routing and firewall lookups don't have to
+// marshal to []byte and pick between the 4- and 16-byte forms themselves.
+//
+// Internally v4 and v6 prefixes share a single PatriciaTree: each key is
+// prefixed with a one-byte family tag (0 for v4, 1 for v6) followed by the
+// address in its native width, so a v4 prefix can never match a v6 lookup
+// or vice versa.
+type IPTree[V any] struct {
+	t *PatriciaTree
+}
+
+// NewIPTree returns an empty IPTree
+func NewIPTree[V any]() *IPTree[V] {
+	return &IPTree[V]{t: NewPatricia()}
+}
+
+// Len is used to return the number of elements in the tree
+func (t *IPTree[V]) Len() int {
+	return t.t.Len()
+}
+
+const (
+	familyV4 byte = 0
+	familyV6 byte = 1
+)
+
+// ipKey builds the tagged, native-width key used internally for addr.
+func ipKey(addr netip.Addr) []byte {
+	if addr.Is4() {
+		a4 := addr.As4()
+		key := make([]byte, 1+len(a4))
+		key[0] = familyV4
+		copy(key[1:], a4[:])
+		return key
+	}
+	a16 := addr.As16()
+	key := make([]byte, 1+len(a16))
+	key[0] = familyV6
+	copy(key[1:], a16[:])
+	return key
+}
+
+// prefixFromKey is the inverse of ipKey plus a bit length, reconstructing
+// the netip.Prefix that was stored.
+func prefixFromKey(key []byte, bits int) netip.Prefix {
+	var addr netip.Addr
+	if key[0] == familyV4 {
+		addr = netip.AddrFrom4([4]byte(key[1:5]))
+	} else {
+		addr = netip.AddrFrom16([16]byte(key[1:17]))
+	}
+	return netip.PrefixFrom(addr, bits-8)
+}
+
+// AddCIDR inserts v under the prefix p, masking p to its own bit length
+// first so equivalent prefixes (e.g. 10.1.2.3/24 and 10.1.2.0/24) collide
+// on the same key.
+func (t *IPTree[V]) AddCIDR(p netip.Prefix, v V) {
+	p = p.Masked()
+	key := ipKey(p.Addr())
+	t.t.InsertPrefix(key, 8+p.Bits(), v)
+}
+
+// RemoveCIDR removes the exact prefix p, returning the previous value and
+// if it was found.
+func (t *IPTree[V]) RemoveCIDR(p netip.Prefix) (V, bool) {
+	p = p.Masked()
+	key := ipKey(p.Addr())
+	v, ok := t.t.DeletePrefix(key, 8+p.Bits())
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return v.(V), true
+}
+
+// LookupAddr returns the longest matching stored prefix covering a, along
+// with its value.
+func (t *IPTree[V]) LookupAddr(a netip.Addr) (netip.Prefix, V, bool) {
+	key := ipKey(a)
+	matchedKey, matchedBits, v, ok := t.t.LongestPrefixMatch(key)
+	if !ok {
+		var zero V
+		return netip.Prefix{}, zero, false
+	}
+	return prefixFromKey(matchedKey, matchedBits), v.(V), true
+}
+
+// Contains reports whether any stored prefix covers a.
+func (t *IPTree[V]) Contains(a netip.Addr) bool {
+	_, _, ok := t.LookupAddr(a)
+	return ok
+}
+
+// WalkCIDR walks every stored prefix, calling fn for each until it
+// returns true.
+func (t *IPTree[V]) WalkCIDR(fn func(netip.Prefix, V) bool) {
+	t.t.Walk(func(k []byte, bits int, v interface{}) bool {
+		return fn(prefixFromKey(k, bits), v.(V))
+	})
+}
@@ -0,0 +1,112 @@
+package radix
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIPTreeLookupAddr(t *testing.T) {
+	tr := NewIPTree[string]()
+
+	tr.AddCIDR(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	tr.AddCIDR(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	tr.AddCIDR(netip.MustParsePrefix("2001:db8::/32"), "c")
+	tr.AddCIDR(netip.MustParsePrefix("2001:db8:1::/48"), "d")
+	tr.AddCIDR(netip.MustParsePrefix("0.0.0.0/0"), "v4-any")
+	tr.AddCIDR(netip.MustParsePrefix("::/0"), "v6-any")
+
+	if tr.Len() != 6 {
+		t.Fatalf("bad length: %v", tr.Len())
+	}
+
+	type exp struct {
+		addr   string
+		prefix string
+		val    interface{}
+	}
+	cases := []exp{
+		{"10.1.2.3", "10.1.0.0/16", "b"},
+		{"10.2.0.1", "10.0.0.0/8", "a"},
+		{"192.168.0.1", "0.0.0.0/0", "v4-any"},
+		{"2001:db8:1::1", "2001:db8:1::/48", "d"},
+		{"2001:db8:2::1", "2001:db8::/32", "c"},
+		{"2001:db9::1", "::/0", "v6-any"},
+	}
+
+	for _, c := range cases {
+		addr := netip.MustParseAddr(c.addr)
+		p, v, ok := tr.LookupAddr(addr)
+		if !ok {
+			t.Fatalf("expected match for %v", c.addr)
+		}
+		if p.String() != c.prefix || v != c.val {
+			t.Fatalf("mis-match for %v: got %v/%v, want %v/%v", c.addr, p, v, c.prefix, c.val)
+		}
+		if !tr.Contains(addr) {
+			t.Fatalf("Contains false for %v despite a match", c.addr)
+		}
+	}
+
+	if _, _, ok := NewIPTree[string]().LookupAddr(netip.MustParseAddr("1.2.3.4")); ok {
+		t.Fatalf("expected no match on an empty tree")
+	}
+}
+
+func TestIPTreeV4MappedDoesNotCollideWithV4(t *testing.T) {
+	tr := NewIPTree[string]()
+	tr.AddCIDR(netip.MustParsePrefix("1.2.3.0/24"), "v4")
+
+	mapped := netip.MustParseAddr("::ffff:1.2.3.4")
+	if tr.Contains(mapped) {
+		t.Fatalf("a 4-in-6 mapped address must not match a pure v4 prefix")
+	}
+
+	tr.AddCIDR(netip.MustParsePrefix("::ffff:1.2.3.0/120"), "mapped")
+	if !tr.Contains(mapped) {
+		t.Fatalf("expected the mapped prefix to match the mapped address")
+	}
+}
+
+func TestIPTreeRemoveCIDR(t *testing.T) {
+	tr := NewIPTree[int]()
+	tr.AddCIDR(netip.MustParsePrefix("192.168.0.0/16"), 1)
+
+	if _, ok := tr.RemoveCIDR(netip.MustParsePrefix("192.168.1.0/24")); ok {
+		t.Fatalf("unexpected removal of a prefix that was never added")
+	}
+
+	v, ok := tr.RemoveCIDR(netip.MustParsePrefix("192.168.0.0/16"))
+	if !ok || v != 1 {
+		t.Fatalf("bad remove: %v %v", v, ok)
+	}
+	if tr.Contains(netip.MustParseAddr("192.168.0.1")) {
+		t.Fatalf("removed prefix still matches")
+	}
+}
+
+func TestIPTreeWalkCIDR(t *testing.T) {
+	tr := NewIPTree[int]()
+	want := map[string]int{
+		"10.0.0.0/8":    1,
+		"172.16.0.0/12": 2,
+		"2001:db8::/32": 3,
+	}
+	for cidr, v := range want {
+		tr.AddCIDR(netip.MustParsePrefix(cidr), v)
+	}
+
+	got := map[string]int{}
+	tr.WalkCIDR(func(p netip.Prefix, v int) bool {
+		got[p.String()] = v
+		return false
+	})
+
+	if len(got) != len(want) {
+		t.Fatalf("mis-match count: %v %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("mis-match for %v: %v %v", k, got[k], v)
+		}
+	}
+}
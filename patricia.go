@@ -0,0 +1,381 @@
+package radix
+
+// PatriciaWalkFn is used when walking a PatriciaTree. Takes a key, the
+// number of significant bits in that key, and a value, returning if
+// iteration should be terminated.
+type PatriciaWalkFn func(k []byte, bits int, v interface{}) bool
+
+// pleafNode is used to store a (key, bits, value) tuple at a node
+type pleafNode struct {
+	key  []byte
+	bits int
+	val  interface{}
+}
+
+// pnode is a node in a PatriciaTree. Unlike the byte-oriented node used by
+// Tree, the edge leading into a pnode is identified by a single bit rather
+// than a byte, so each node has at most two children. The edge label itself
+// is not copied into the node: instead pnode points at the []byte that was
+// passed to InsertPrefix and records the bit range within it, avoiding a
+// bit-shuffling copy on every split.
+type pnode struct {
+	leaf *pleafNode
+
+	// prefixKey/prefixOff/prefixLen describe the bits of the incoming
+	// edge: prefixLen bits starting at bit offset prefixOff within
+	// prefixKey.
+	prefixKey []byte
+	prefixOff int
+	prefixLen int
+
+	children [2]*pnode
+}
+
+func (n *pnode) isLeaf() bool {
+	return n.leaf != nil
+}
+
+// PatriciaTree implements a bit-granular Patricia trie keyed on
+// (bytes, bitLen) pairs rather than whole bytes. This makes it possible to
+// store CIDR-style prefixes of any length (/23, /56, /72, ...) and to
+// answer longest-prefix-match queries against them, which Tree cannot do
+// since it only branches on whole-byte boundaries. Because of that
+// difference in branching (a sorted, arbitrary-width edge list keyed by
+// byte vs. a fixed two-way array keyed by bit) pnode and node can't share
+// a walk or node representation; what the two trees' traversal code does
+// share - the "descend while the next edge fully matches, bail on the
+// first mismatch" step - is factored into descendStep below and reused by
+// every lookup here instead of being copied per method.
+type PatriciaTree struct {
+	root *pnode
+	size int
+}
+
+// NewPatricia returns an empty PatriciaTree
+func NewPatricia() *PatriciaTree {
+	return &PatriciaTree{root: &pnode{}}
+}
+
+// Len is used to return the number of elements in the tree
+func (t *PatriciaTree) Len() int {
+	return t.size
+}
+
+// getBit returns the bit at absolute position pos in key, treating key as
+// a big-endian bit string (bit 0 is the MSB of key[0]). Positions beyond
+// the end of key read as 0.
+func getBit(key []byte, pos int) byte {
+	idx := pos / 8
+	if idx < 0 || idx >= len(key) {
+		return 0
+	}
+	return (key[idx] >> uint(7-pos%8)) & 1
+}
+
+// commonBits returns the number of matching leading bits, up to length,
+// between aKey starting at aOff and bKey starting at bOff.
+func commonBits(aKey []byte, aOff int, bKey []byte, bOff, length int) int {
+	i := 0
+	for i < length {
+		if getBit(aKey, aOff+i) != getBit(bKey, bOff+i) {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+// InsertPrefix is used to add a new entry or update an existing entry for
+// the prefix given by the first bits bits of key. Returns the old value
+// and a bool indicating if any was set.
+func (t *PatriciaTree) InsertPrefix(key []byte, bits int, v interface{}) (interface{}, bool) {
+	var parent *pnode
+	var parentBit byte
+	n := t.root
+	pos := 0
+	for {
+		// Handle key exhaustion
+		if pos == bits {
+			if n.isLeaf() {
+				old := n.leaf.val
+				n.leaf.val = v
+				return old, true
+			}
+			n.leaf = &pleafNode{key: key, bits: bits, val: v}
+			t.size++
+			return nil, false
+		}
+
+		bit := getBit(key, pos)
+		parent = n
+		parentBit = bit
+		child := n.children[bit]
+
+		// No edge, create one
+		if child == nil {
+			n.children[bit] = &pnode{
+				prefixKey: key,
+				prefixOff: pos,
+				prefixLen: bits - pos,
+				leaf:      &pleafNode{key: key, bits: bits, val: v},
+			}
+			t.size++
+			return nil, false
+		}
+
+		// Determine the longest shared prefix of the search key and
+		// the child's edge label
+		remaining := bits - pos
+		length := child.prefixLen
+		if remaining < length {
+			length = remaining
+		}
+		common := commonBits(key, pos, child.prefixKey, child.prefixOff, length)
+		if common == child.prefixLen {
+			pos += common
+			n = child
+			continue
+		}
+
+		// Split the edge
+		t.size++
+		split := &pnode{
+			prefixKey: key,
+			prefixOff: pos,
+			prefixLen: common,
+		}
+		parent.children[parentBit] = split
+
+		childBit := getBit(child.prefixKey, child.prefixOff+common)
+		child.prefixOff += common
+		child.prefixLen -= common
+		split.children[childBit] = child
+
+		pos += common
+		if pos == bits {
+			split.leaf = &pleafNode{key: key, bits: bits, val: v}
+			return nil, false
+		}
+
+		newBit := getBit(key, pos)
+		split.children[newBit] = &pnode{
+			prefixKey: key,
+			prefixOff: pos,
+			prefixLen: bits - pos,
+			leaf:      &pleafNode{key: key, bits: bits, val: v},
+		}
+		return nil, false
+	}
+}
+
+// descendStep attempts to follow n's single child edge matching the next
+// bits of key starting at pos, where limit bounds how many bits of key are
+// available from pos onward. The child edge must match in full within that
+// limit; a shorter match (edge runs past limit, or diverges partway) is
+// reported as failure, since every caller - exact lookup, delete, and the
+// two longest-match walks below - treats a partial edge match as "no such
+// prefix" rather than something to act on. This is the one place that
+// descent logic lives; GetPrefix, DeletePrefix, LongestPrefixMatch and
+// WalkPrefixesCovering all drive it with their own loop around it instead
+// of re-implementing the bit-matching themselves.
+func (n *pnode) descendStep(key []byte, pos, limit int) (bit byte, child *pnode, newPos int, ok bool) {
+	bit = getBit(key, pos)
+	c := n.children[bit]
+	if c == nil {
+		return bit, nil, pos, false
+	}
+	remaining := limit - pos
+	length := c.prefixLen
+	if remaining < length {
+		length = remaining
+	}
+	if length != c.prefixLen || commonBits(key, pos, c.prefixKey, c.prefixOff, length) != c.prefixLen {
+		return bit, nil, pos, false
+	}
+	return bit, c, pos + c.prefixLen, true
+}
+
+// descend walks from the root matching key bit by bit, stopping either at
+// key exhaustion or at the first edge that does not fully match. It
+// reports the node reached and whether the full bits bits were consumed
+// exactly on a node boundary.
+func (t *PatriciaTree) descend(key []byte, bits int) (n *pnode, pos int, exact bool) {
+	n = t.root
+	pos = 0
+	for pos < bits {
+		_, child, newPos, ok := n.descendStep(key, pos, bits)
+		if !ok {
+			return n, pos, false
+		}
+		pos = newPos
+		n = child
+	}
+	return n, pos, true
+}
+
+// GetPrefix is used to look up the exact prefix given by the first bits
+// bits of key, returning the value and if it was found.
+func (t *PatriciaTree) GetPrefix(key []byte, bits int) (interface{}, bool) {
+	n, pos, exact := t.descend(key, bits)
+	if !exact || pos != bits || !n.isLeaf() {
+		return nil, false
+	}
+	return n.leaf.val, true
+}
+
+// DeletePrefix removes the exact prefix given by the first bits bits of
+// key, returning the previous value and if it was deleted. Like
+// Tree.Delete, a node left with no children is unlinked from its parent,
+// and a node (or its parent) left with exactly one child and no leaf is
+// folded into that child, so repeated insert/delete churn doesn't grow
+// the trie with permanent single-child pass-through nodes.
+func (t *PatriciaTree) DeletePrefix(key []byte, bits int) (interface{}, bool) {
+	var parent *pnode
+	var parentBit byte
+	n := t.root
+	pos := 0
+	for pos < bits {
+		bit, child, newPos, ok := n.descendStep(key, pos, bits)
+		if !ok {
+			return nil, false
+		}
+		parent = n
+		parentBit = bit
+		pos = newPos
+		n = child
+	}
+	if !n.isLeaf() {
+		return nil, false
+	}
+
+	leaf := n.leaf
+	n.leaf = nil
+	t.size--
+
+	if parent != nil && n.numChildren() == 0 {
+		parent.children[parentBit] = nil
+		if parent != t.root && !parent.isLeaf() && parent.numChildren() == 1 {
+			parent.mergeChild()
+		}
+	} else if n != t.root && n.numChildren() == 1 {
+		n.mergeChild()
+	}
+
+	return leaf.val, true
+}
+
+// numChildren returns the number of non-nil children of n.
+func (n *pnode) numChildren() int {
+	count := 0
+	for _, c := range n.children {
+		if c != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// mergeChild folds n's single remaining child into n, concatenating
+// their edge bits into a freshly allocated, left-aligned buffer since the
+// two edges may originate from different inserted keys.
+func (n *pnode) mergeChild() {
+	var child *pnode
+	for _, c := range n.children {
+		if c != nil {
+			child = c
+			break
+		}
+	}
+	n.prefixKey = concatBits(n.prefixKey, n.prefixOff, n.prefixLen, child.prefixKey, child.prefixOff, child.prefixLen)
+	n.prefixOff = 0
+	n.prefixLen += child.prefixLen
+	n.leaf = child.leaf
+	n.children = child.children
+}
+
+// concatBits returns a fresh, left-aligned buffer holding the aLen bits
+// of aKey starting at aOff, followed by the bLen bits of bKey starting at
+// bOff.
+func concatBits(aKey []byte, aOff, aLen int, bKey []byte, bOff, bLen int) []byte {
+	total := aLen + bLen
+	out := make([]byte, (total+7)/8)
+	for i := 0; i < aLen; i++ {
+		if getBit(aKey, aOff+i) == 1 {
+			out[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+	for i := 0; i < bLen; i++ {
+		pos := aLen + i
+		if getBit(bKey, bOff+i) == 1 {
+			out[pos/8] |= 1 << uint(7-pos%8)
+		}
+	}
+	return out
+}
+
+// LongestPrefixMatch descends addr bit by bit and returns the most
+// specific stored prefix covering addr, i.e. the deepest stored leaf
+// encountered along the path from the root. This is the main reason to
+// reach for PatriciaTree over Tree: routing and ACL tables key on prefixes
+// of varying length and need the best, not merely an exact, match.
+func (t *PatriciaTree) LongestPrefixMatch(addr []byte) (matchedKey []byte, matchedBits int, v interface{}, ok bool) {
+	n := t.root
+	pos := 0
+	addrBits := len(addr) * 8
+	for {
+		if n.isLeaf() {
+			matchedKey, matchedBits, v, ok = n.leaf.key, n.leaf.bits, n.leaf.val, true
+		}
+		if pos >= addrBits {
+			return
+		}
+		_, child, newPos, stepOK := n.descendStep(addr, pos, addrBits)
+		if !stepOK {
+			return
+		}
+		pos = newPos
+		n = child
+	}
+}
+
+// WalkPrefixesCovering enumerates every stored prefix that covers addr,
+// from least to most specific, calling fn for each. Unlike
+// LongestPrefixMatch, which only reports the single best match, this is
+// useful for ACL/routing tables where several overlapping prefixes may
+// all need to apply to the same address.
+func (t *PatriciaTree) WalkPrefixesCovering(addr []byte, fn PatriciaWalkFn) {
+	n := t.root
+	pos := 0
+	addrBits := len(addr) * 8
+	for {
+		if n.isLeaf() && fn(n.leaf.key, n.leaf.bits, n.leaf.val) {
+			return
+		}
+		if pos >= addrBits {
+			return
+		}
+		_, child, newPos, ok := n.descendStep(addr, pos, addrBits)
+		if !ok {
+			return
+		}
+		pos = newPos
+		n = child
+	}
+}
+
+// Walk does a pre-order walk of the whole tree, mirroring Tree.Walk.
+func (t *PatriciaTree) Walk(fn PatriciaWalkFn) {
+	recursivePatriciaWalk(t.root, fn)
+}
+
+func recursivePatriciaWalk(n *pnode, fn PatriciaWalkFn) bool {
+	if n.leaf != nil && fn(n.leaf.key, n.leaf.bits, n.leaf.val) {
+		return true
+	}
+	for _, c := range n.children {
+		if c != nil && recursivePatriciaWalk(c, fn) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package radix
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPatriciaInsertGet(t *testing.T) {
+	p := NewPatricia()
+
+	type entry struct {
+		ip   string
+		bits int
+		val  interface{}
+	}
+	entries := []entry{
+		{"10.0.0.0", 8, "a"},
+		{"10.1.0.0", 23, "b"},
+		{"10.1.0.0", 28, "c"},
+		{"2001:db8::", 56, "d"},
+		{"2001:db8::", 72, "e"},
+	}
+
+	for _, e := range entries {
+		key := net.ParseIP(e.ip)
+		if v4 := key.To4(); v4 != nil && e.bits <= 32 {
+			key = v4
+		} else {
+			key = key.To16()
+		}
+		if _, updated := p.InsertPrefix(key, e.bits, e.val); updated {
+			t.Fatalf("unexpected update for %v/%d", e.ip, e.bits)
+		}
+	}
+
+	if p.Len() != len(entries) {
+		t.Fatalf("bad length: %v %v", p.Len(), len(entries))
+	}
+
+	for _, e := range entries {
+		key := net.ParseIP(e.ip)
+		if v4 := key.To4(); v4 != nil && e.bits <= 32 {
+			key = v4
+		} else {
+			key = key.To16()
+		}
+		got, ok := p.GetPrefix(key, e.bits)
+		if !ok {
+			t.Fatalf("missing prefix %v/%d", e.ip, e.bits)
+		}
+		if got != e.val {
+			t.Fatalf("value mismatch for %v/%d: %v %v", e.ip, e.bits, got, e.val)
+		}
+	}
+
+	if _, ok := p.GetPrefix(net.ParseIP("10.1.0.0").To4(), 24); ok {
+		t.Fatalf("unexpected match for a prefix that was never inserted")
+	}
+}
+
+func TestPatriciaLongestPrefixMatch(t *testing.T) {
+	p := NewPatricia()
+
+	type insert struct {
+		cidr string
+		val  interface{}
+	}
+	inserts := []insert{
+		{"2001:db8::/32", "net"},
+		{"2001:db8:1::/48", "site"},
+		{"2001:db8:1:2::/64", "subnet"},
+		{"2001:db8:1:2::/72", "host-range"},
+	}
+	for _, in := range inserts {
+		_, cidr, err := net.ParseCIDR(in.cidr)
+		if err != nil {
+			t.Fatalf("bad cidr %v: %v", in.cidr, err)
+		}
+		ones, _ := cidr.Mask.Size()
+		p.InsertPrefix(cidr.IP.To16(), ones, in.val)
+	}
+
+	type exp struct {
+		addr string
+		bits int
+		val  interface{}
+	}
+	cases := []exp{
+		{"2001:db8:1:2::1", 72, "host-range"},
+		{"2001:db8:1:2:ffff::1", 64, "subnet"},
+		{"2001:db8:1:3::1", 48, "site"},
+		{"2001:db8:2::1", 32, "net"},
+		{"2001:db9::1", 0, nil},
+	}
+
+	for _, c := range cases {
+		addr := net.ParseIP(c.addr).To16()
+		key, bits, val, ok := p.LongestPrefixMatch(addr)
+		if c.bits == 0 {
+			if ok {
+				t.Fatalf("expected no match for %v, got %v/%d", c.addr, key, bits)
+			}
+			continue
+		}
+		if !ok {
+			t.Fatalf("expected match for %v", c.addr)
+		}
+		if bits != c.bits || val != c.val {
+			t.Fatalf("mis-match for %v: got %v/%d, want bits %d val %v", c.addr, val, bits, c.bits, c.val)
+		}
+	}
+}
+
+func TestPatriciaWalkPrefixesCovering(t *testing.T) {
+	p := NewPatricia()
+
+	_, allowNet, _ := net.ParseCIDR("10.0.0.0/8")
+	_, allowSub, _ := net.ParseCIDR("10.1.0.0/16")
+	_, other, _ := net.ParseCIDR("192.168.0.0/16")
+
+	for _, n := range []*net.IPNet{allowNet, allowSub, other} {
+		ones, _ := n.Mask.Size()
+		p.InsertPrefix(n.IP.To4(), ones, n.String())
+	}
+
+	var covering []string
+	p.WalkPrefixesCovering(net.ParseIP("10.1.2.3").To4(), func(k []byte, bits int, v interface{}) bool {
+		covering = append(covering, v.(string))
+		return false
+	})
+
+	if len(covering) != 2 || covering[0] != "10.0.0.0/8" || covering[1] != "10.1.0.0/16" {
+		t.Fatalf("unexpected covering set: %v", covering)
+	}
+}
+
+func TestPatriciaDeletePrefix(t *testing.T) {
+	p := NewPatricia()
+
+	p.InsertPrefix(net.ParseIP("10.0.0.0").To4(), 8, "a")
+	p.InsertPrefix(net.ParseIP("10.1.0.0").To4(), 16, "b")
+
+	if p.Len() != 2 {
+		t.Fatalf("bad length: %v", p.Len())
+	}
+
+	if _, ok := p.DeletePrefix(net.ParseIP("10.2.0.0").To4(), 16); ok {
+		t.Fatalf("unexpected delete of a prefix that was never inserted")
+	}
+
+	val, ok := p.DeletePrefix(net.ParseIP("10.1.0.0").To4(), 16)
+	if !ok || val != "b" {
+		t.Fatalf("bad delete: %v %v", val, ok)
+	}
+	if p.Len() != 1 {
+		t.Fatalf("bad length after delete: %v", p.Len())
+	}
+
+	if _, ok := p.GetPrefix(net.ParseIP("10.1.0.0").To4(), 16); ok {
+		t.Fatalf("deleted prefix still found")
+	}
+	if got, ok := p.GetPrefix(net.ParseIP("10.0.0.0").To4(), 8); !ok || got != "a" {
+		t.Fatalf("unrelated prefix disturbed by delete: %v %v", got, ok)
+	}
+}
+
+func countPNodes(n *pnode) int {
+	if n == nil {
+		return 0
+	}
+	count := 1
+	for _, c := range n.children {
+		count += countPNodes(c)
+	}
+	return count
+}
+
+func TestPatriciaDeletePrefixCompaction(t *testing.T) {
+	p := NewPatricia()
+	p.InsertPrefix([]byte{0x00}, 8, "a")
+	p.InsertPrefix([]byte{0x01}, 8, "b")
+
+	before := countPNodes(p.root) - 1 // exclude the always-present root sentinel
+	if before != 3 {
+		t.Fatalf("expected 3 internal nodes before delete (a split node plus two leaves), got %d", before)
+	}
+
+	if _, ok := p.DeletePrefix([]byte{0x01}, 8); !ok {
+		t.Fatalf("expected delete to succeed")
+	}
+
+	after := countPNodes(p.root) - 1
+	if after != 1 {
+		t.Fatalf("expected the now-single-child split node to merge away, got %d nodes", after)
+	}
+	if v, ok := p.GetPrefix([]byte{0x00}, 8); !ok || v != "a" {
+		t.Fatalf("surviving prefix broken after merge: %v %v", v, ok)
+	}
+}
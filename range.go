@@ -0,0 +1,140 @@
+package radix
+
+import "bytes"
+
+// WalkRange visits every key in [lo, hi], in sorted order, descending only
+// into subtrees whose stored prefix could still fall in that range. A
+// subtree rooted under a given path can only contain keys sharing that
+// path as a prefix, so the range check needs nothing more than comparing
+// the path accumulated so far against lo and hi.
+func (t *Tree) WalkRange(lo, hi []byte, fn WalkFn) {
+	if bytes.Compare(lo, hi) > 0 {
+		return
+	}
+	walkRange(t.root, nil, lo, hi, fn)
+}
+
+// rangeOverlaps reports whether path - a prefix shared by every key in
+// some subtree - can still fall within [lo, hi].
+func rangeOverlaps(path, lo, hi []byte) bool {
+	if bytes.Compare(path, hi) > 0 {
+		return false
+	}
+	if bytes.Compare(path, lo) < 0 && !bytes.HasPrefix(lo, path) {
+		return false
+	}
+	return true
+}
+
+func walkRange(n *node, path, lo, hi []byte, fn WalkFn) bool {
+	full := append(append([]byte(nil), path...), n.prefix...)
+	if !rangeOverlaps(full, lo, hi) {
+		return false
+	}
+	if n.leaf != nil {
+		k := n.leaf.key
+		if bytes.Compare(k, lo) >= 0 && bytes.Compare(k, hi) <= 0 {
+			if fn(k, n.leaf.val) {
+				return true
+			}
+		}
+	}
+	for _, e := range n.edges {
+		if walkRange(e.node, full, lo, hi, fn) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subtract returns a new tree containing the entries of t whose key is
+// not also a key of other.
+func (t *Tree) Subtract(other *Tree) *Tree {
+	return mergeSet(t, other, func(inT, inOther bool) bool { return inT && !inOther })
+}
+
+// Intersect returns a new tree containing the entries common to t and
+// other, keeping t's value when a key appears in both.
+func (t *Tree) Intersect(other *Tree) *Tree {
+	return mergeSet(t, other, func(inT, inOther bool) bool { return inT && inOther })
+}
+
+// Union returns a new tree containing every entry of t and other. When a
+// key appears in both, t's value wins.
+func (t *Tree) Union(other *Tree) *Tree {
+	return mergeSet(t, other, func(inT, inOther bool) bool { return inT || inOther })
+}
+
+// treeCursor produces a Tree's leaves in sorted order one at a time,
+// via an explicit stack rather than a recursive Walk, so a caller can
+// pull from two trees in lockstep without ever materializing either
+// one's full entry list.
+type treeCursor struct {
+	stack []*node
+}
+
+func newTreeCursor(root *node) *treeCursor {
+	return &treeCursor{stack: []*node{root}}
+}
+
+// next returns the next leaf in sorted order, or ok=false once the tree
+// is exhausted.
+func (c *treeCursor) next() (key []byte, val interface{}, ok bool) {
+	for len(c.stack) > 0 {
+		n := c.stack[len(c.stack)-1]
+		c.stack = c.stack[:len(c.stack)-1]
+		for i := len(n.edges) - 1; i >= 0; i-- {
+			c.stack = append(c.stack, n.edges[i].node)
+		}
+		if n.leaf != nil {
+			return n.leaf.key, n.leaf.val, true
+		}
+	}
+	return nil, nil, false
+}
+
+// mergeSet does a synchronized descent of t's and other's sorted
+// entries, pulling one key at a time from each side via a treeCursor
+// rather than doing a Get per key or materializing either tree's full
+// entry list up front.
+func mergeSet(t, other *Tree, include func(inT, inOther bool) bool) *Tree {
+	out := New()
+	ca := newTreeCursor(t.root)
+	cb := newTreeCursor(other.root)
+
+	ak, av, aok := ca.next()
+	bk, bv, bok := cb.next()
+	for aok && bok {
+		switch bytes.Compare(ak, bk) {
+		case 0:
+			if include(true, true) {
+				out.Insert(ak, av)
+			}
+			ak, av, aok = ca.next()
+			bk, bv, bok = cb.next()
+		case -1:
+			if include(true, false) {
+				out.Insert(ak, av)
+			}
+			ak, av, aok = ca.next()
+		default:
+			if include(false, true) {
+				out.Insert(bk, bv)
+			}
+			bk, bv, bok = cb.next()
+		}
+	}
+	for aok {
+		if include(true, false) {
+			out.Insert(ak, av)
+		}
+		ak, av, aok = ca.next()
+	}
+	for bok {
+		if include(false, true) {
+			out.Insert(bk, bv)
+		}
+		bk, bv, bok = cb.next()
+	}
+	return out
+}
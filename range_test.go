@@ -0,0 +1,144 @@
+package radix
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWalkRange(t *testing.T) {
+	r := New()
+	keys := [][]byte{{1}, {5}, {10}, {10, 1}, {20}, {30}}
+	for _, k := range keys {
+		r.Insert(k, nil)
+	}
+
+	var out [][]byte
+	r.WalkRange([]byte{5}, []byte{20}, func(k []byte, v interface{}) bool {
+		out = append(out, k)
+		return false
+	})
+
+	want := [][]byte{{5}, {10}, {10, 1}, {20}}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("mis-match: %v %v", out, want)
+	}
+}
+
+func TestWalkRangeEmpty(t *testing.T) {
+	r := New()
+	r.Insert([]byte{1}, nil)
+	r.Insert([]byte{9}, nil)
+
+	var out [][]byte
+	r.WalkRange([]byte{20}, []byte{10}, func(k []byte, v interface{}) bool {
+		out = append(out, k)
+		return false
+	})
+	if len(out) != 0 {
+		t.Fatalf("expected no keys for an inverted range, got %v", out)
+	}
+}
+
+func TestSetOps(t *testing.T) {
+	a := New()
+	a.Insert([]byte("x"), 1)
+	a.Insert([]byte("y"), 2)
+
+	b := New()
+	b.Insert([]byte("y"), 99)
+	b.Insert([]byte("z"), 3)
+
+	sub := a.Subtract(b)
+	if sub.Len() != 1 {
+		t.Fatalf("bad subtract length: %v", sub.Len())
+	}
+	if v, ok := sub.Get([]byte("x")); !ok || v != 1 {
+		t.Fatalf("bad subtract result: %v %v", v, ok)
+	}
+
+	inter := a.Intersect(b)
+	if inter.Len() != 1 {
+		t.Fatalf("bad intersect length: %v", inter.Len())
+	}
+	if v, _ := inter.Get([]byte("y")); v != 2 {
+		t.Fatalf("intersect should keep the receiver's value: %v", v)
+	}
+
+	union := a.Union(b)
+	if union.Len() != 3 {
+		t.Fatalf("bad union length: %v", union.Len())
+	}
+	if v, _ := union.Get([]byte("y")); v != 2 {
+		t.Fatalf("union should keep the receiver's value on overlap: %v", v)
+	}
+}
+
+func BenchmarkWalkRangeIPv6(b *testing.B) {
+	r := New()
+	for i := 0; i < 1000000; i++ {
+		gen := generateIPv6()
+		r.Insert(gen.To16(), true)
+	}
+	lo := make([]byte, 16)
+	hi := make([]byte, 16)
+	copy(lo, []byte{0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01, 0x00, 0x02, 0x00})
+	copy(hi, []byte{0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01, 0x00, 0x02, 0xff})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.WalkRange(lo, hi, func(k []byte, v interface{}) bool { return false })
+	}
+}
+
+func BenchmarkWalkRangeNaiveIPv6(b *testing.B) {
+	r := New()
+	for i := 0; i < 1000000; i++ {
+		gen := generateIPv6()
+		r.Insert(gen.To16(), true)
+	}
+	lo := make([]byte, 16)
+	hi := make([]byte, 16)
+	copy(lo, []byte{0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01, 0x00, 0x02, 0x00})
+	copy(hi, []byte{0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01, 0x00, 0x02, 0xff})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Walk(func(k []byte, v interface{}) bool {
+			if bytes.Compare(k, lo) >= 0 && bytes.Compare(k, hi) <= 0 {
+				_ = v
+			}
+			return false
+		})
+	}
+}
+
+func BenchmarkIntersectIPv6(b *testing.B) {
+	a := New()
+	c := New()
+	for i := 0; i < 1000000; i++ {
+		a.Insert(generateIPv6().To16(), true)
+		c.Insert(generateIPv6().To16(), true)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.Intersect(c)
+	}
+}
+
+func BenchmarkIntersectNaiveIPv6(b *testing.B) {
+	a := New()
+	c := New()
+	for i := 0; i < 1000000; i++ {
+		a.Insert(generateIPv6().To16(), true)
+		c.Insert(generateIPv6().To16(), true)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out := New()
+		a.Walk(func(k []byte, v interface{}) bool {
+			if _, ok := c.Get(k); ok {
+				out.Insert(k, v)
+			}
+			return false
+		})
+	}
+}